@@ -0,0 +1,272 @@
+package sqlcertcache
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// Dialect abstracts the SQL syntax differences between database engines so
+// Cache can generate correct schema and query strings for each of them.
+type Dialect interface {
+	// BlobType returns the column type used to store binary data.
+	BlobType() string
+	// TimestampType returns the column type used to store timestamps.
+	TimestampType() string
+	// Placeholder returns the bind parameter placeholder for the i'th
+	// argument of a query, where i is 1-indexed.
+	Placeholder(i int) string
+	// QuoteIdentifier quotes name the way this dialect requires so it can
+	// be used safely as a column name, even when it collides with a
+	// reserved word (as "key" does on MySQL and SQL Server).
+	QuoteIdentifier(name string) string
+	// AddColumnQuery returns a statement that adds column to tableName,
+	// with type and constraints def, if it isn't already present. This
+	// lets a table created by an older version of this package pick up
+	// columns added by a later one (e.g. nonce, key_id) without losing
+	// its existing rows.
+	AddColumnQuery(tableName, column, def string) string
+	// CurrentTimestampExpr returns this dialect's SQL expression for the
+	// current timestamp, used to back-fill a NOT NULL column (created_at)
+	// added by AddColumnQuery on rows that predate it.
+	CurrentTimestampExpr() string
+	// CreateTableQuery returns a statement that creates tableName with the
+	// given parenthesized column-definition body if it doesn't already
+	// exist. SQL Server has no CREATE TABLE IF NOT EXISTS, so this is a
+	// Dialect method rather than a hard-coded string.
+	CreateTableQuery(tableName, body string) string
+	// UpsertQuery returns a statement that atomically inserts a new row
+	// or updates the existing row sharing the same key. columns is the
+	// full column list in storage order, with columns[0] always "key".
+	// updatable is the subset of columns to overwrite when the key
+	// already exists; columns absent from it (e.g. created_at) are kept
+	// at their original value across updates.
+	UpsertQuery(tableName string, columns, updatable []string) string
+}
+
+// quoteAll quotes every column in columns using d's identifier quoting.
+func quoteAll(d Dialect, columns []string) []string {
+	quoted := make([]string, len(columns))
+	for i, col := range columns {
+		quoted[i] = d.QuoteIdentifier(col)
+	}
+	return quoted
+}
+
+// PostgresDialect targets PostgreSQL and PostgreSQL-compatible databases.
+// It is also the fallback used when the driver cannot be identified, to
+// preserve this package's historical behavior.
+type PostgresDialect struct{}
+
+// BlobType implements Dialect.
+func (PostgresDialect) BlobType() string { return "bytea" }
+
+// TimestampType implements Dialect.
+func (PostgresDialect) TimestampType() string { return "timestamptz" }
+
+// Placeholder implements Dialect.
+func (PostgresDialect) Placeholder(i int) string { return fmt.Sprintf("$%d", i) }
+
+// QuoteIdentifier implements Dialect.
+func (PostgresDialect) QuoteIdentifier(name string) string { return `"` + name + `"` }
+
+// AddColumnQuery implements Dialect.
+func (d PostgresDialect) AddColumnQuery(tableName, column, def string) string {
+	return fmt.Sprintf(`ALTER TABLE %s ADD COLUMN IF NOT EXISTS %s %s;`, tableName, d.QuoteIdentifier(column), def)
+}
+
+// CurrentTimestampExpr implements Dialect.
+func (PostgresDialect) CurrentTimestampExpr() string { return "now()" }
+
+// CreateTableQuery implements Dialect.
+func (PostgresDialect) CreateTableQuery(tableName, body string) string {
+	return fmt.Sprintf(`create table if not exists %s (%s);`, tableName, body)
+}
+
+// UpsertQuery implements Dialect.
+func (d PostgresDialect) UpsertQuery(tableName string, columns, updatable []string) string {
+	placeholders := make([]string, len(columns))
+	for i := range columns {
+		placeholders[i] = d.Placeholder(i + 1)
+	}
+
+	updates := make([]string, len(updatable))
+	for i, col := range updatable {
+		q := d.QuoteIdentifier(col)
+		updates[i] = fmt.Sprintf("%s = EXCLUDED.%s", q, q)
+	}
+
+	return fmt.Sprintf(`INSERT INTO %s (%s) VALUES (%s) ON CONFLICT (%s) DO UPDATE SET %s`,
+		tableName, strings.Join(quoteAll(d, columns), ", "), strings.Join(placeholders, ", "),
+		d.QuoteIdentifier("key"), strings.Join(updates, ", "))
+}
+
+// MySQLDialect targets MySQL and MariaDB.
+type MySQLDialect struct{}
+
+// BlobType implements Dialect.
+func (MySQLDialect) BlobType() string { return "longblob" }
+
+// TimestampType implements Dialect.
+func (MySQLDialect) TimestampType() string { return "datetime" }
+
+// Placeholder implements Dialect.
+func (MySQLDialect) Placeholder(int) string { return "?" }
+
+// QuoteIdentifier implements Dialect.
+func (MySQLDialect) QuoteIdentifier(name string) string { return "`" + name + "`" }
+
+// AddColumnQuery implements Dialect. MySQL has no "ADD COLUMN IF NOT
+// EXISTS", so callers must tolerate a "duplicate column name" error when
+// re-running this against a table that already has column.
+func (d MySQLDialect) AddColumnQuery(tableName, column, def string) string {
+	return fmt.Sprintf(`ALTER TABLE %s ADD COLUMN %s %s;`, tableName, d.QuoteIdentifier(column), def)
+}
+
+// CurrentTimestampExpr implements Dialect.
+func (MySQLDialect) CurrentTimestampExpr() string { return "CURRENT_TIMESTAMP" }
+
+// CreateTableQuery implements Dialect.
+func (MySQLDialect) CreateTableQuery(tableName, body string) string {
+	return fmt.Sprintf(`create table if not exists %s (%s);`, tableName, body)
+}
+
+// UpsertQuery implements Dialect.
+func (d MySQLDialect) UpsertQuery(tableName string, columns, updatable []string) string {
+	placeholders := make([]string, len(columns))
+	for i := range columns {
+		placeholders[i] = d.Placeholder(i + 1)
+	}
+
+	updates := make([]string, len(updatable))
+	for i, col := range updatable {
+		q := d.QuoteIdentifier(col)
+		updates[i] = fmt.Sprintf("%s = VALUES(%s)", q, q)
+	}
+
+	return fmt.Sprintf(`INSERT INTO %s (%s) VALUES (%s) ON DUPLICATE KEY UPDATE %s`,
+		tableName, strings.Join(quoteAll(d, columns), ", "), strings.Join(placeholders, ", "), strings.Join(updates, ", "))
+}
+
+// SQLiteDialect targets SQLite.
+type SQLiteDialect struct{}
+
+// BlobType implements Dialect.
+func (SQLiteDialect) BlobType() string { return "blob" }
+
+// TimestampType implements Dialect.
+func (SQLiteDialect) TimestampType() string { return "datetime" }
+
+// Placeholder implements Dialect.
+func (SQLiteDialect) Placeholder(int) string { return "?" }
+
+// QuoteIdentifier implements Dialect.
+func (SQLiteDialect) QuoteIdentifier(name string) string { return `"` + name + `"` }
+
+// AddColumnQuery implements Dialect. SQLite has no "ADD COLUMN IF NOT
+// EXISTS", so callers must tolerate a "duplicate column name" error when
+// re-running this against a table that already has column.
+func (d SQLiteDialect) AddColumnQuery(tableName, column, def string) string {
+	return fmt.Sprintf(`ALTER TABLE %s ADD COLUMN %s %s;`, tableName, d.QuoteIdentifier(column), def)
+}
+
+// CurrentTimestampExpr implements Dialect.
+func (SQLiteDialect) CurrentTimestampExpr() string { return "CURRENT_TIMESTAMP" }
+
+// CreateTableQuery implements Dialect.
+func (SQLiteDialect) CreateTableQuery(tableName, body string) string {
+	return fmt.Sprintf(`create table if not exists %s (%s);`, tableName, body)
+}
+
+// UpsertQuery implements Dialect.
+func (d SQLiteDialect) UpsertQuery(tableName string, columns, updatable []string) string {
+	placeholders := make([]string, len(columns))
+	for i := range columns {
+		placeholders[i] = d.Placeholder(i + 1)
+	}
+
+	updates := make([]string, len(updatable))
+	for i, col := range updatable {
+		q := d.QuoteIdentifier(col)
+		updates[i] = fmt.Sprintf("%s = excluded.%s", q, q)
+	}
+
+	return fmt.Sprintf(`INSERT INTO %s (%s) VALUES (%s) ON CONFLICT (%s) DO UPDATE SET %s`,
+		tableName, strings.Join(quoteAll(d, columns), ", "), strings.Join(placeholders, ", "),
+		d.QuoteIdentifier("key"), strings.Join(updates, ", "))
+}
+
+// MSSQLDialect targets Microsoft SQL Server.
+type MSSQLDialect struct{}
+
+// BlobType implements Dialect.
+func (MSSQLDialect) BlobType() string { return "varbinary(max)" }
+
+// TimestampType implements Dialect.
+func (MSSQLDialect) TimestampType() string { return "datetime2" }
+
+// Placeholder implements Dialect.
+func (MSSQLDialect) Placeholder(i int) string { return fmt.Sprintf("@p%d", i) }
+
+// QuoteIdentifier implements Dialect.
+func (MSSQLDialect) QuoteIdentifier(name string) string { return "[" + name + "]" }
+
+// AddColumnQuery implements Dialect.
+func (d MSSQLDialect) AddColumnQuery(tableName, column, def string) string {
+	return fmt.Sprintf(`IF COL_LENGTH('%s', '%s') IS NULL ALTER TABLE %s ADD %s %s;`,
+		tableName, column, tableName, d.QuoteIdentifier(column), def)
+}
+
+// CurrentTimestampExpr implements Dialect.
+func (MSSQLDialect) CurrentTimestampExpr() string { return "SYSUTCDATETIME()" }
+
+// CreateTableQuery implements Dialect. SQL Server has no CREATE TABLE IF
+// NOT EXISTS, so existence is checked against sys.tables first.
+func (MSSQLDialect) CreateTableQuery(tableName, body string) string {
+	return fmt.Sprintf(`IF NOT EXISTS (SELECT * FROM sys.tables WHERE name = '%s')
+CREATE TABLE %s (%s);`, tableName, tableName, body)
+}
+
+// UpsertQuery implements Dialect.
+func (d MSSQLDialect) UpsertQuery(tableName string, columns, updatable []string) string {
+	quotedCols := quoteAll(d, columns)
+
+	srcCols := make([]string, len(columns))
+	insertVals := make([]string, len(columns))
+	for i := range columns {
+		srcCols[i] = fmt.Sprintf("%s AS %s", d.Placeholder(i+1), quotedCols[i])
+		insertVals[i] = "src." + quotedCols[i]
+	}
+
+	updates := make([]string, len(updatable))
+	for i, col := range updatable {
+		q := d.QuoteIdentifier(col)
+		updates[i] = fmt.Sprintf("target.%s = src.%s", q, q)
+	}
+
+	keyCol := d.QuoteIdentifier("key")
+	return fmt.Sprintf(`MERGE INTO %s WITH (HOLDLOCK) AS target
+USING (SELECT %s) AS src (%s)
+ON target.%s = src.%s
+WHEN MATCHED THEN UPDATE SET %s
+WHEN NOT MATCHED THEN INSERT (%s) VALUES (%s);`,
+		tableName, strings.Join(srcCols, ", "), strings.Join(quotedCols, ", "), keyCol, keyCol,
+		strings.Join(updates, ", "), strings.Join(quotedCols, ", "), strings.Join(insertVals, ", "))
+}
+
+// detectDialect infers a Dialect from conn's underlying driver type name,
+// falling back to PostgresDialect when the driver is not recognized.
+func detectDialect(conn *sql.DB) Dialect {
+	driverType := strings.ToLower(fmt.Sprintf("%T", conn.Driver()))
+
+	switch {
+	case strings.Contains(driverType, "mysql"):
+		return MySQLDialect{}
+	case strings.Contains(driverType, "sqlite"):
+		return SQLiteDialect{}
+	case strings.Contains(driverType, "mssql") || strings.Contains(driverType, "sqlserver"):
+		return MSSQLDialect{}
+	default:
+		return PostgresDialect{}
+	}
+}
@@ -0,0 +1,151 @@
+package sqlcertcache
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDialect_QuoteIdentifier(t *testing.T) {
+	tests := []struct {
+		name    string
+		dialect Dialect
+		want    string
+	}{
+		{"postgres", PostgresDialect{}, `"key"`},
+		{"mysql", MySQLDialect{}, "`key`"},
+		{"sqlite", SQLiteDialect{}, `"key"`},
+		{"mssql", MSSQLDialect{}, "[key]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.dialect.QuoteIdentifier("key"); got != tt.want {
+				t.Errorf("QuoteIdentifier(%q) = %q, want %q", "key", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDialect_UpsertQuery_QuotesReservedKeyColumn(t *testing.T) {
+	columns := []string{"key", "data", "created_at"}
+	updatable := []string{"data"}
+
+	tests := []struct {
+		name     string
+		dialect  Dialect
+		wantKeys []string
+	}{
+		{"postgres", PostgresDialect{}, []string{`"key"`}},
+		{"mysql", MySQLDialect{}, []string{"`key`"}},
+		{"sqlite", SQLiteDialect{}, []string{`"key"`}},
+		{"mssql", MSSQLDialect{}, []string{"[key]"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query := tt.dialect.UpsertQuery("certs", columns, updatable)
+			for _, want := range tt.wantKeys {
+				if !strings.Contains(query, want) {
+					t.Errorf("UpsertQuery() = %q, want it to contain %q", query, want)
+				}
+			}
+			// None of these dialects may emit the bare, unquoted "key"
+			// identifier: it's a reserved word on MySQL and SQL Server.
+			if strings.Contains(query, " key ") || strings.Contains(query, "(key,") {
+				t.Errorf("UpsertQuery() = %q, contains an unquoted key column", query)
+			}
+		})
+	}
+}
+
+func TestDialect_AddColumnQuery_QuotesColumn(t *testing.T) {
+	tests := []struct {
+		name    string
+		dialect Dialect
+		want    string
+	}{
+		{"postgres", PostgresDialect{}, `"nonce"`},
+		{"mysql", MySQLDialect{}, "`nonce`"},
+		{"sqlite", SQLiteDialect{}, `"nonce"`},
+		{"mssql", MSSQLDialect{}, "[nonce]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query := tt.dialect.AddColumnQuery("certs", "nonce", "blob")
+			if !strings.Contains(query, tt.want) {
+				t.Errorf("AddColumnQuery() = %q, want it to contain %q", query, tt.want)
+			}
+			if !strings.Contains(query, "certs") {
+				t.Errorf("AddColumnQuery() = %q, want it to reference the table name", query)
+			}
+		})
+	}
+}
+
+func TestDialect_CurrentTimestampExpr(t *testing.T) {
+	tests := []struct {
+		name    string
+		dialect Dialect
+		want    string
+	}{
+		{"postgres", PostgresDialect{}, "now()"},
+		{"mysql", MySQLDialect{}, "CURRENT_TIMESTAMP"},
+		{"sqlite", SQLiteDialect{}, "CURRENT_TIMESTAMP"},
+		{"mssql", MSSQLDialect{}, "SYSUTCDATETIME()"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.dialect.CurrentTimestampExpr(); got != tt.want {
+				t.Errorf("CurrentTimestampExpr() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDialect_CreateTableQuery(t *testing.T) {
+	tests := []struct {
+		name    string
+		dialect Dialect
+		want    []string
+	}{
+		{"postgres", PostgresDialect{}, []string{"create table if not exists certs", "col def"}},
+		{"mysql", MySQLDialect{}, []string{"create table if not exists certs", "col def"}},
+		{"sqlite", SQLiteDialect{}, []string{"create table if not exists certs", "col def"}},
+		{"mssql", MSSQLDialect{}, []string{"IF NOT EXISTS", "sys.tables", "CREATE TABLE certs", "col def"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query := tt.dialect.CreateTableQuery("certs", "col def")
+			for _, want := range tt.want {
+				if !strings.Contains(query, want) {
+					t.Errorf("CreateTableQuery() = %q, want it to contain %q", query, want)
+				}
+			}
+		})
+	}
+}
+
+func TestDialect_Placeholder(t *testing.T) {
+	tests := []struct {
+		name    string
+		dialect Dialect
+		i       int
+		want    string
+	}{
+		{"postgres", PostgresDialect{}, 2, "$2"},
+		{"mysql", MySQLDialect{}, 2, "?"},
+		{"sqlite", SQLiteDialect{}, 2, "?"},
+		{"mssql", MSSQLDialect{}, 2, "@p2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.dialect.Placeholder(tt.i); got != tt.want {
+				t.Errorf("Placeholder(%d) = %q, want %q", tt.i, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,134 @@
+package sqlcertcache
+
+import (
+	"crypto/rand"
+	"errors"
+
+	"golang.org/x/crypto/nacl/secretbox"
+)
+
+// EncryptionStrategy encrypts certificate data before it is persisted and
+// decrypts it on read. Implementations must be safe for concurrent use.
+type EncryptionStrategy interface {
+	// Encrypt returns the ciphertext and nonce for the given plaintext.
+	Encrypt(plaintext []byte) (ciphertext []byte, nonce []byte, err error)
+	// Decrypt returns the plaintext for the given ciphertext and nonce.
+	Decrypt(ciphertext, nonce []byte) ([]byte, error)
+}
+
+// KeyedEncryptionStrategy is implemented by strategies that support key
+// rotation: the key used to encrypt a row is recorded alongside it (by ID)
+// so that old rows keep decrypting correctly after the current key changes.
+// Cache stores and looks up the key ID in a dedicated column.
+type KeyedEncryptionStrategy interface {
+	EncryptionStrategy
+
+	// CurrentKeyID returns the key ID that Encrypt used (or will use) for
+	// new writes, to be stored alongside the ciphertext.
+	CurrentKeyID() string
+	// DecryptWithKeyID decrypts ciphertext using the key identified by keyID
+	// rather than the current key, enabling reads after key rotation.
+	DecryptWithKeyID(ciphertext, nonce []byte, keyID string) ([]byte, error)
+}
+
+// noopEncryptionStrategy stores certificate data unmodified. It is the
+// default strategy, preserving the historical plaintext behavior.
+type noopEncryptionStrategy struct{}
+
+func (noopEncryptionStrategy) Encrypt(plaintext []byte) ([]byte, []byte, error) {
+	return plaintext, nil, nil
+}
+
+func (noopEncryptionStrategy) Decrypt(ciphertext, nonce []byte) ([]byte, error) {
+	return ciphertext, nil
+}
+
+const secretboxNonceSize = 24
+
+// Keyring resolves a 32-byte secretbox key by its key ID, enabling key
+// rotation: new writes use CurrentKeyID, while reads look up whichever
+// key ID was stored alongside the row.
+type Keyring interface {
+	// CurrentKeyID returns the key ID that should be used for new writes.
+	CurrentKeyID() string
+	// Key returns the 32-byte key for the given key ID.
+	Key(keyID string) (*[32]byte, error)
+}
+
+// staticKeyring is a Keyring backed by a single fixed key.
+type staticKeyring struct {
+	keyID string
+	key   [32]byte
+}
+
+// NewStaticKeyring returns a Keyring that always resolves to key under keyID.
+func NewStaticKeyring(keyID string, key [32]byte) Keyring {
+	return &staticKeyring{keyID: keyID, key: key}
+}
+
+func (k *staticKeyring) CurrentKeyID() string {
+	return k.keyID
+}
+
+func (k *staticKeyring) Key(keyID string) (*[32]byte, error) {
+	if keyID != k.keyID {
+		return nil, errors.New("sqlcertcache: unknown key id " + keyID)
+	}
+	return &k.key, nil
+}
+
+// secretboxEncryptionStrategy implements KeyedEncryptionStrategy using NaCl
+// secretbox (XSalsa20-Poly1305). Keys are resolved through a Keyring so
+// callers can rotate keys without losing the ability to decrypt old rows.
+type secretboxEncryptionStrategy struct {
+	keyring Keyring
+}
+
+// NewSecretboxEncryptionStrategy returns a KeyedEncryptionStrategy that seals
+// certificate data with NaCl secretbox, using keyring to resolve keys by ID.
+func NewSecretboxEncryptionStrategy(keyring Keyring) EncryptionStrategy {
+	return &secretboxEncryptionStrategy{keyring: keyring}
+}
+
+func (s *secretboxEncryptionStrategy) CurrentKeyID() string {
+	return s.keyring.CurrentKeyID()
+}
+
+func (s *secretboxEncryptionStrategy) Encrypt(plaintext []byte) ([]byte, []byte, error) {
+	key, err := s.keyring.Key(s.keyring.CurrentKeyID())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var nonce [secretboxNonceSize]byte
+	if _, err := rand.Read(nonce[:]); err != nil {
+		return nil, nil, err
+	}
+
+	ciphertext := secretbox.Seal(nil, plaintext, &nonce, key)
+	return ciphertext, nonce[:], nil
+}
+
+func (s *secretboxEncryptionStrategy) Decrypt(ciphertext, nonce []byte) ([]byte, error) {
+	return s.DecryptWithKeyID(ciphertext, nonce, s.keyring.CurrentKeyID())
+}
+
+func (s *secretboxEncryptionStrategy) DecryptWithKeyID(ciphertext, nonce []byte, keyID string) ([]byte, error) {
+	if len(nonce) != secretboxNonceSize {
+		return nil, errors.New("sqlcertcache: invalid nonce length")
+	}
+
+	key, err := s.keyring.Key(keyID)
+	if err != nil {
+		return nil, err
+	}
+
+	var nonceArr [secretboxNonceSize]byte
+	copy(nonceArr[:], nonce)
+
+	plaintext, ok := secretbox.Open(nil, ciphertext, &nonceArr, key)
+	if !ok {
+		return nil, errors.New("sqlcertcache: failed to decrypt data, invalid key or corrupted ciphertext")
+	}
+	return plaintext, nil
+}
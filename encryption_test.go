@@ -0,0 +1,88 @@
+package sqlcertcache
+
+import "testing"
+
+func TestNoopEncryptionStrategy(t *testing.T) {
+	var s noopEncryptionStrategy
+
+	ciphertext, nonce, err := s.Encrypt([]byte("plaintext"))
+	if err != nil {
+		t.Fatalf("Encrypt() error = %v", err)
+	}
+	if nonce != nil {
+		t.Fatalf("Encrypt() nonce = %v, want nil", nonce)
+	}
+	if string(ciphertext) != "plaintext" {
+		t.Fatalf("Encrypt() ciphertext = %q, want %q", ciphertext, "plaintext")
+	}
+
+	plaintext, err := s.Decrypt(ciphertext, nonce)
+	if err != nil {
+		t.Fatalf("Decrypt() error = %v", err)
+	}
+	if string(plaintext) != "plaintext" {
+		t.Fatalf("Decrypt() = %q, want %q", plaintext, "plaintext")
+	}
+}
+
+func TestSecretboxEncryptionStrategy_RoundTrip(t *testing.T) {
+	var key [32]byte
+	copy(key[:], "0123456789abcdef0123456789abcdef")
+	keyring := NewStaticKeyring("k1", key)
+	s := NewSecretboxEncryptionStrategy(keyring)
+
+	want := []byte("super secret certificate data")
+	ciphertext, nonce, err := s.Encrypt(want)
+	if err != nil {
+		t.Fatalf("Encrypt() error = %v", err)
+	}
+	if string(ciphertext) == string(want) {
+		t.Fatalf("Encrypt() did not transform the plaintext")
+	}
+
+	got, err := s.Decrypt(ciphertext, nonce)
+	if err != nil {
+		t.Fatalf("Decrypt() error = %v", err)
+	}
+	if string(got) != string(want) {
+		t.Fatalf("Decrypt() = %q, want %q", got, want)
+	}
+}
+
+func TestSecretboxEncryptionStrategy_KeyRotation(t *testing.T) {
+	var oldKey, newKey [32]byte
+	copy(oldKey[:], "old-key-old-key-old-key-old-key!")
+	copy(newKey[:], "new-key-new-key-new-key-new-key!")
+
+	oldRing := NewStaticKeyring("old", oldKey)
+	s := NewSecretboxEncryptionStrategy(oldRing)
+
+	want := []byte("cert encrypted under the old key")
+	ciphertext, nonce, err := s.Encrypt(want)
+	if err != nil {
+		t.Fatalf("Encrypt() error = %v", err)
+	}
+
+	keyed := s.(KeyedEncryptionStrategy)
+	oldKeyID := keyed.CurrentKeyID()
+	if oldKeyID != "old" {
+		t.Fatalf("CurrentKeyID() = %q, want %q", oldKeyID, "old")
+	}
+
+	// Rotate to a new keyring; decrypting the row written under the old
+	// key must still work by its recorded key ID.
+	s = NewSecretboxEncryptionStrategy(NewStaticKeyring("new", newKey))
+	keyed = s.(KeyedEncryptionStrategy)
+
+	got, err := keyed.DecryptWithKeyID(ciphertext, nonce, oldKeyID)
+	if err != nil {
+		t.Fatalf("DecryptWithKeyID() error = %v", err)
+	}
+	if string(got) != string(want) {
+		t.Fatalf("DecryptWithKeyID() = %q, want %q", got, want)
+	}
+
+	if _, err := keyed.DecryptWithKeyID(ciphertext, nonce, "unknown"); err == nil {
+		t.Fatalf("DecryptWithKeyID() with unknown key id: expected an error")
+	}
+}
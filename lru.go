@@ -0,0 +1,79 @@
+package sqlcertcache
+
+import "container/list"
+
+// defaultMaxCacheEntries is the size of the in-memory cache when the caller
+// does not configure WithMaxCacheEntries.
+const defaultMaxCacheEntries = 256
+
+// lruCache is a fixed-size least-recently-used cache of certificate data.
+// It is not safe for concurrent use; callers must hold their own lock.
+type lruCache struct {
+	maxEntries int
+	ll         *list.List
+	items      map[string]*list.Element
+}
+
+type lruEntry struct {
+	key   string
+	value []byte
+}
+
+func newLRUCache(maxEntries int) *lruCache {
+	if maxEntries <= 0 {
+		maxEntries = defaultMaxCacheEntries
+	}
+	return &lruCache{
+		maxEntries: maxEntries,
+		ll:         list.New(),
+		items:      make(map[string]*list.Element),
+	}
+}
+
+// Get returns the value for key, if present, and moves it to the front.
+func (c *lruCache) Get(key string) ([]byte, bool) {
+	elem, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	c.ll.MoveToFront(elem)
+	return elem.Value.(*lruEntry).value, true
+}
+
+// Add inserts or overwrites the value for key, evicting the least recently
+// used entry if the cache is at capacity.
+func (c *lruCache) Add(key string, value []byte) {
+	if elem, ok := c.items[key]; ok {
+		elem.Value.(*lruEntry).value = value
+		c.ll.MoveToFront(elem)
+		return
+	}
+
+	elem := c.ll.PushFront(&lruEntry{key: key, value: value})
+	c.items[key] = elem
+
+	if c.ll.Len() > c.maxEntries {
+		oldest := c.ll.Back()
+		if oldest != nil {
+			c.ll.Remove(oldest)
+			delete(c.items, oldest.Value.(*lruEntry).key)
+		}
+	}
+}
+
+// Remove deletes the entry for key, if present.
+func (c *lruCache) Remove(key string) {
+	if elem, ok := c.items[key]; ok {
+		c.ll.Remove(elem)
+		delete(c.items, key)
+	}
+}
+
+// Clear discards every entry, e.g. after a resync where individual keys
+// affected by a gap can no longer be identified.
+func (c *lruCache) Clear() {
+	c.ll.Init()
+	for key := range c.items {
+		delete(c.items, key)
+	}
+}
@@ -0,0 +1,69 @@
+package sqlcertcache
+
+import "testing"
+
+func TestLRUCache_GetAdd(t *testing.T) {
+	c := newLRUCache(10)
+
+	if _, ok := c.Get("a"); ok {
+		t.Fatalf("Get() on empty cache: ok = true, want false")
+	}
+
+	c.Add("a", []byte("1"))
+	got, ok := c.Get("a")
+	if !ok || string(got) != "1" {
+		t.Fatalf("Get(%q) = %q, %v, want %q, true", "a", got, ok, "1")
+	}
+}
+
+func TestLRUCache_EvictsLeastRecentlyUsed(t *testing.T) {
+	c := newLRUCache(2)
+
+	c.Add("a", []byte("1"))
+	c.Add("b", []byte("2"))
+	// touch "a" so "b" becomes the least recently used entry
+	c.Get("a")
+	c.Add("c", []byte("3"))
+
+	if _, ok := c.Get("b"); ok {
+		t.Fatalf("Get(%q) after eviction: ok = true, want false", "b")
+	}
+	if _, ok := c.Get("a"); !ok {
+		t.Fatalf("Get(%q): ok = false, want true", "a")
+	}
+	if _, ok := c.Get("c"); !ok {
+		t.Fatalf("Get(%q): ok = false, want true", "c")
+	}
+}
+
+func TestLRUCache_AddOverwritesExistingEntry(t *testing.T) {
+	c := newLRUCache(10)
+
+	c.Add("a", []byte("first"))
+	c.Add("a", []byte("second"))
+
+	got, ok := c.Get("a")
+	if !ok || string(got) != "second" {
+		t.Fatalf("Get(%q) = %q, %v, want %q, true", "a", got, ok, "second")
+	}
+}
+
+func TestLRUCache_RemoveAndClear(t *testing.T) {
+	c := newLRUCache(10)
+
+	c.Add("a", []byte("1"))
+	c.Add("b", []byte("2"))
+
+	c.Remove("a")
+	if _, ok := c.Get("a"); ok {
+		t.Fatalf("Get(%q) after Remove: ok = true, want false", "a")
+	}
+
+	c.Clear()
+	if _, ok := c.Get("b"); ok {
+		t.Fatalf("Get(%q) after Clear: ok = true, want false", "b")
+	}
+	if got := c.ll.Len(); got != 0 {
+		t.Fatalf("ll.Len() after Clear = %d, want 0", got)
+	}
+}
@@ -0,0 +1,96 @@
+package sqlcertcache
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+)
+
+// notifyNodeIDLen is the length, in hex characters, of the random ID every
+// Cache generates in EnableNotify to recognize (and ignore) its own
+// published invalidations.
+const notifyNodeIDLen = 16
+
+// Invalidator propagates cache invalidation events across nodes sharing
+// the same backing table, so that a Put or Delete on one node evicts the
+// now-stale entry from every other node's in-memory LRU. The pgnotify
+// subpackage implements this over PostgreSQL LISTEN/NOTIFY; callers on
+// other databases can plug in Redis pub/sub, NATS, or similar instead.
+type Invalidator interface {
+	// Publish announces msg, an opaque invalidation message built by
+	// Cache, to every subscriber.
+	Publish(ctx context.Context, msg string) error
+	// Subscribe invokes onInvalidate for every message published by any
+	// node (including this one), blocking until ctx is canceled.
+	// Implementations that can detect a gap in delivery (e.g. a dropped
+	// LISTEN connection that may have missed notifications) should call
+	// onInvalidate("") to signal that the caller can no longer tell
+	// which keys were affected.
+	Subscribe(ctx context.Context, onInvalidate func(msg string))
+}
+
+// EnableNotify wires invalidator into the cache: Put and Delete publish the
+// affected key through it, and a background goroutine subscribes to
+// invalidations, evicting the corresponding entry from this node's
+// in-memory LRU when any other node publishes it. It must be called once,
+// after construction and before serving traffic.
+func (c *Cache) EnableNotify(ctx context.Context, invalidator Invalidator) {
+	c.invalidator = invalidator
+	c.notifyNodeID = newNotifyNodeID()
+
+	go invalidator.Subscribe(ctx, func(msg string) {
+		if msg == "" {
+			// A gap was signaled and we can't know which keys were
+			// missed, so drop everything local; Get will repopulate
+			// from the database on next access.
+			c.certsMu.Lock()
+			c.certs.Clear()
+			c.certsMu.Unlock()
+			return
+		}
+
+		nodeID, key, ok := decodeNotifyMessage(msg)
+		if !ok || nodeID == c.notifyNodeID {
+			// Either malformed, or this is the echo of our own publish
+			// (we already hold the fresh value locally).
+			return
+		}
+
+		c.certsMu.Lock()
+		c.certs.Remove(key)
+		c.certsMu.Unlock()
+	})
+}
+
+// notify publishes key's invalidation, tagged with this node's ID so peers
+// (including a LISTEN echoed back to this same node) can tell who wrote
+// it. It is best-effort: a failure to propagate doesn't undo the SQL write
+// that already succeeded, so callers aren't expected to treat Put/Delete
+// as failed because of it.
+func (c *Cache) notify(ctx context.Context, key string) {
+	if c.invalidator == nil {
+		return
+	}
+	c.invalidator.Publish(ctx, c.notifyNodeID+key)
+}
+
+func newNotifyNodeID() string {
+	b := make([]byte, notifyNodeIDLen/2)
+	if _, err := rand.Read(b); err != nil {
+		// crypto/rand failing means the platform's entropy source is
+		// broken; fall back to a fixed ID rather than panicking. Own-echo
+		// filtering still works, but any other node that also falls back
+		// shares this ID, and each then ignores the other's invalidations.
+		return "0000000000000000"
+	}
+	return hex.EncodeToString(b)
+}
+
+// decodeNotifyMessage splits a published message into its origin node ID
+// and the invalidated key.
+func decodeNotifyMessage(msg string) (nodeID, key string, ok bool) {
+	if len(msg) < notifyNodeIDLen {
+		return "", "", false
+	}
+	return msg[:notifyNodeIDLen], msg[notifyNodeIDLen:], true
+}
@@ -0,0 +1,124 @@
+package sqlcertcache
+
+import (
+	"context"
+	"sync"
+	"testing"
+)
+
+func TestNewNotifyNodeID_Length(t *testing.T) {
+	id := newNotifyNodeID()
+	if len(id) != notifyNodeIDLen {
+		t.Fatalf("newNotifyNodeID() = %q, want length %d", id, notifyNodeIDLen)
+	}
+}
+
+func TestDecodeNotifyMessage(t *testing.T) {
+	nodeID := newNotifyNodeID()
+	msg := nodeID + "example.com"
+
+	gotNodeID, gotKey, ok := decodeNotifyMessage(msg)
+	if !ok {
+		t.Fatalf("decodeNotifyMessage(%q) ok = false, want true", msg)
+	}
+	if gotNodeID != nodeID || gotKey != "example.com" {
+		t.Fatalf("decodeNotifyMessage(%q) = %q, %q, want %q, %q", msg, gotNodeID, gotKey, nodeID, "example.com")
+	}
+
+	if _, _, ok := decodeNotifyMessage("too-short"); ok {
+		t.Fatalf("decodeNotifyMessage() on a message shorter than the node id: ok = true, want false")
+	}
+}
+
+// fakeInvalidator is an in-memory Invalidator for testing EnableNotify
+// without a real pub/sub backend. Subscribe hands its onInvalidate callback
+// back to the test via the subscribed channel, then blocks until ctx is
+// canceled, like a real Invalidator would.
+type fakeInvalidator struct {
+	mu           sync.Mutex
+	published    []string
+	onInvalidate func(string)
+	subscribed   chan struct{}
+}
+
+func (f *fakeInvalidator) Publish(ctx context.Context, msg string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.published = append(f.published, msg)
+	return nil
+}
+
+func (f *fakeInvalidator) Subscribe(ctx context.Context, onInvalidate func(msg string)) {
+	f.onInvalidate = onInvalidate
+	close(f.subscribed)
+	<-ctx.Done()
+}
+
+func TestEnableNotify_IgnoresOwnEcho(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	c := &Cache{certs: newLRUCache(10)}
+	inv := &fakeInvalidator{subscribed: make(chan struct{})}
+	c.EnableNotify(ctx, inv)
+	<-inv.subscribed
+
+	c.certs.Add("example.com", []byte("cert"))
+
+	// A message tagged with this node's own ID is this node's echo of its
+	// own Put, and must not evict the value it just cached.
+	inv.onInvalidate(c.notifyNodeID + "example.com")
+	if _, ok := c.certs.Get("example.com"); !ok {
+		t.Fatalf("entry evicted by its own node's echoed invalidation")
+	}
+
+	// A message from a different node for the same key must evict it.
+	inv.onInvalidate("ffffffffffffffff" + "example.com")
+	if _, ok := c.certs.Get("example.com"); ok {
+		t.Fatalf("entry survived an invalidation published by another node")
+	}
+}
+
+func TestEnableNotify_GapClearsLocalCache(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	c := &Cache{certs: newLRUCache(10)}
+	inv := &fakeInvalidator{subscribed: make(chan struct{})}
+	c.EnableNotify(ctx, inv)
+	<-inv.subscribed
+
+	c.certs.Add("a.example.com", []byte("1"))
+	c.certs.Add("b.example.com", []byte("2"))
+
+	inv.onInvalidate("")
+
+	if _, ok := c.certs.Get("a.example.com"); ok {
+		t.Fatalf("cache not cleared after a gap signal")
+	}
+	if _, ok := c.certs.Get("b.example.com"); ok {
+		t.Fatalf("cache not cleared after a gap signal")
+	}
+}
+
+func TestCacheNotify_TagsPublishedMessageWithNodeID(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	c := &Cache{certs: newLRUCache(10)}
+	inv := &fakeInvalidator{subscribed: make(chan struct{})}
+	c.EnableNotify(ctx, inv)
+	<-inv.subscribed
+
+	c.notify(ctx, "example.com")
+
+	inv.mu.Lock()
+	defer inv.mu.Unlock()
+	if len(inv.published) != 1 {
+		t.Fatalf("Publish called %d times, want 1", len(inv.published))
+	}
+	nodeID, key, ok := decodeNotifyMessage(inv.published[0])
+	if !ok || nodeID != c.notifyNodeID || key != "example.com" {
+		t.Fatalf("published message = %q, want node id %q and key %q", inv.published[0], c.notifyNodeID, "example.com")
+	}
+}
@@ -0,0 +1,47 @@
+package sqlcertcache
+
+import "time"
+
+// Option configures optional behavior on a Cache. Options are applied in
+// order by NewWithOptions.
+type Option func(*Cache)
+
+// WithEncryption configures strategy to encrypt certificate data before it
+// is written to the database and decrypt it on read. If not provided, the
+// cache falls back to noopEncryptionStrategy, which stores data as-is.
+//
+// Use NewSecretboxEncryptionStrategy for a built-in NaCl secretbox
+// implementation with key-rotation support.
+func WithEncryption(strategy EncryptionStrategy) Option {
+	return func(c *Cache) {
+		c.encryption = strategy
+	}
+}
+
+// WithMaxCacheEntries bounds the size of the in-memory LRU cache that sits
+// in front of the database, evicting the least recently used entry once n
+// entries are held. If not provided, defaultMaxCacheEntries is used.
+func WithMaxCacheEntries(n int) Option {
+	return func(c *Cache) {
+		c.certs = newLRUCache(n)
+	}
+}
+
+// WithDialect overrides the Dialect that New/NewWithOptions would otherwise
+// auto-detect from the *sql.DB driver, for drivers that can't be identified
+// reliably or to force a specific SQL syntax.
+func WithDialect(dialect Dialect) Option {
+	return func(c *Cache) {
+		c.dialect = dialect
+	}
+}
+
+// WithDefaultTTL sets the expiry Put assigns to entries whose data does not
+// parse as a PEM certificate chain (account keys, HTTP-01 tokens), so that
+// StartReaper can eventually clean them up too. Certificates are always
+// expired from their own leaf's NotAfter, regardless of this setting.
+func WithDefaultTTL(ttl time.Duration) Option {
+	return func(c *Cache) {
+		c.defaultTTL = ttl
+	}
+}
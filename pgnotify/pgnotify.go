@@ -0,0 +1,81 @@
+// Package pgnotify provides a PostgreSQL-backed sqlcertcache.Invalidator
+// built on LISTEN/NOTIFY. It lives in its own module-internal package so
+// that importing github.com/lib/pq, and thus requiring a PostgreSQL
+// client, is opt-in: sqlcertcache.Invalidator is generic, and callers on
+// MySQL, SQLite, or another pub/sub backend shouldn't have to pull in a
+// Postgres driver they never use.
+package pgnotify
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/goenning/sqlcertcache"
+	"github.com/lib/pq"
+)
+
+// Making sure that we're adhering to the sqlcertcache.Invalidator interface.
+var _ sqlcertcache.Invalidator = (*Invalidator)(nil)
+
+// Invalidator is the built-in sqlcertcache.Invalidator for PostgreSQL. It
+// publishes with pg_notify() over the regular connection pool and
+// subscribes with a pq.Listener, so every node sharing the table learns
+// about a Put/Delete as soon as it commits.
+type Invalidator struct {
+	conn     *sql.DB
+	channel  string
+	listener *pq.Listener
+}
+
+// New returns an Invalidator for channel. conn is used to publish, and can
+// be the same *sql.DB passed to sqlcertcache.New; connStr is a standalone
+// DSN used to open the dedicated LISTEN connection that pq.Listener
+// requires.
+func New(conn *sql.DB, connStr, channel string) *Invalidator {
+	return &Invalidator{
+		conn:     conn,
+		channel:  channel,
+		listener: pq.NewListener(connStr, 10*time.Second, time.Minute, nil),
+	}
+}
+
+// Publish implements sqlcertcache.Invalidator.
+func (p *Invalidator) Publish(ctx context.Context, key string) error {
+	_, err := p.conn.ExecContext(ctx, `SELECT pg_notify($1, $2)`, p.channel, key)
+	return err
+}
+
+// Subscribe implements sqlcertcache.Invalidator. It blocks until ctx is
+// canceled.
+func (p *Invalidator) Subscribe(ctx context.Context, onInvalidate func(key string)) {
+	for {
+		if err := p.listener.Listen(p.channel); err == nil {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(5 * time.Second):
+		}
+	}
+	defer p.listener.Close()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case n := <-p.listener.Notify:
+			if n == nil {
+				// pq.Listener sends a nil Notification after
+				// reconnecting, meaning notifications may have been
+				// missed while disconnected.
+				onInvalidate("")
+				continue
+			}
+			onInvalidate(n.Extra)
+		case <-time.After(90 * time.Second):
+			go p.listener.Ping()
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package sqlcertcache
+
+import (
+	"context"
+	"crypto/x509"
+	"database/sql"
+	"encoding/pem"
+	"time"
+)
+
+// Entry describes a row stored in the cache, as returned by List.
+type Entry struct {
+	Key       string
+	CreatedAt time.Time
+	// ExpiresAt is nil when the entry has no expiry, e.g. the default
+	// strategy applied to data that isn't a certificate and no default
+	// TTL was configured.
+	ExpiresAt *time.Time
+}
+
+// List returns every entry currently stored in the cache, including ones
+// already past their expiry but not yet removed by the reaper.
+func (c *Cache) List(ctx context.Context) ([]Entry, error) {
+	rows, err := c.conn.QueryContext(ctx, c.listQuery)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []Entry
+	for rows.Next() {
+		var e Entry
+		var expiresAt sql.NullTime
+		if err := rows.Scan(&e.Key, &e.CreatedAt, &expiresAt); err != nil {
+			return nil, err
+		}
+		if expiresAt.Valid {
+			e.ExpiresAt = &expiresAt.Time
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+// StartReaper launches a background goroutine that deletes expired rows
+// every interval, until ctx is canceled.
+func (c *Cache) StartReaper(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				c.reap(ctx)
+			}
+		}
+	}()
+}
+
+// reap deletes every row whose expires_at has passed. Errors are dropped
+// since StartReaper runs unattended; the next tick will retry.
+func (c *Cache) reap(ctx context.Context) {
+	c.conn.ExecContext(ctx, c.reapQuery, time.Now())
+}
+
+// expiresAt computes the expires_at value to store alongside data: the
+// leaf certificate's NotAfter when data parses as a PEM certificate chain,
+// otherwise c.defaultTTL from now, or no expiry at all.
+func (c *Cache) expiresAt(data []byte, now time.Time) sql.NullTime {
+	if notAfter, ok := leafCertificateNotAfter(data); ok {
+		return sql.NullTime{Time: notAfter, Valid: true}
+	}
+	if c.defaultTTL > 0 {
+		return sql.NullTime{Time: now.Add(c.defaultTTL), Valid: true}
+	}
+	return sql.NullTime{}
+}
+
+// leafCertificateNotAfter returns the NotAfter time of the leaf certificate
+// in a PEM-encoded chain, if data is one. autocert stores the private key
+// block before the certificate chain, so this skips any non-certificate
+// blocks (and any PEM data that doesn't contain one at all) rather than
+// only looking at the first block.
+func leafCertificateNotAfter(data []byte) (time.Time, bool) {
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			return time.Time{}, false
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return time.Time{}, false
+		}
+		return cert.NotAfter, true
+	}
+}
@@ -0,0 +1,94 @@
+package sqlcertcache
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+)
+
+// testCertPEM returns autocert-formatted PEM data: a private key block
+// followed by a leaf certificate with the given NotAfter.
+func testCertPEM(t *testing.T, notAfter time.Time) []byte {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+	keyBytes, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("MarshalECPrivateKey() error = %v", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		NotBefore:    notAfter.Add(-time.Hour),
+		NotAfter:     notAfter,
+	}
+	certBytes, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("CreateCertificate() error = %v", err)
+	}
+
+	var out []byte
+	out = append(out, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyBytes})...)
+	out = append(out, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certBytes})...)
+	return out
+}
+
+func TestLeafCertificateNotAfter_SkipsPrivateKeyBlock(t *testing.T) {
+	want := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	data := testCertPEM(t, want)
+
+	got, ok := leafCertificateNotAfter(data)
+	if !ok {
+		t.Fatalf("leafCertificateNotAfter() ok = false, want true")
+	}
+	if !got.Equal(want) {
+		t.Fatalf("leafCertificateNotAfter() = %v, want %v", got, want)
+	}
+}
+
+func TestLeafCertificateNotAfter_NonCertificateData(t *testing.T) {
+	if _, ok := leafCertificateNotAfter([]byte("not a certificate")); ok {
+		t.Fatalf("leafCertificateNotAfter() ok = true, want false")
+	}
+}
+
+func TestExpiresAt_PrefersLeafCertNotAfter(t *testing.T) {
+	c := &Cache{defaultTTL: time.Hour}
+	now := time.Now()
+	// x509 encodes NotAfter with whole-second precision.
+	want := now.Add(30 * 24 * time.Hour).Truncate(time.Second)
+
+	got := c.expiresAt(testCertPEM(t, want), now)
+	if !got.Valid || !got.Time.Equal(want) {
+		t.Fatalf("expiresAt() = %v, want %v (the leaf's NotAfter, not the default TTL)", got, want)
+	}
+}
+
+func TestExpiresAt_FallsBackToDefaultTTL(t *testing.T) {
+	c := &Cache{defaultTTL: time.Hour}
+	now := time.Now()
+
+	got := c.expiresAt([]byte("account-key-or-http01-token"), now)
+	want := now.Add(time.Hour)
+	if !got.Valid || !got.Time.Equal(want) {
+		t.Fatalf("expiresAt() = %v, want %v", got, want)
+	}
+}
+
+func TestExpiresAt_NoExpiryWithoutDefaultTTL(t *testing.T) {
+	c := &Cache{}
+	now := time.Now()
+
+	got := c.expiresAt([]byte("account-key-or-http01-token"), now)
+	if got.Valid {
+		t.Fatalf("expiresAt() = %v, want an invalid (no-expiry) value", got)
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"fmt"
 	"strings"
 	"sync"
+	"time"
 
 	"golang.org/x/crypto/acme/autocert"
 )
@@ -19,99 +20,196 @@ var _ autocert.Cache = (*Cache)(nil)
 
 // Cache provides a SQL backend to the autocert cache.
 type Cache struct {
-	conn        *sql.DB
-	certs       map[string][]byte
-	certsMu     sync.RWMutex
-	getQuery    string
-	insertQuery string
-	updateQuery string
-	deleteQuery string
+	conn         *sql.DB
+	certs        *lruCache
+	certsMu      sync.Mutex
+	dialect      Dialect
+	getQuery     string
+	upsertQuery  string
+	deleteQuery  string
+	listQuery    string
+	reapQuery    string
+	encryption   EncryptionStrategy
+	defaultTTL   time.Duration
+	invalidator  Invalidator
+	notifyNodeID string
 }
 
 // New creates an cache instance that can be used with autocert.Cache.
 // It returns any errors that could happen while connecting to SQL.
 func New(conn *sql.DB, tableName string) (*Cache, error) {
+	return NewWithOptions(conn, tableName)
+}
+
+// NewWithOptions creates a cache instance like New, applying any opts on
+// top of the default configuration. Unless overridden with WithDialect, the
+// SQL dialect is auto-detected from conn's driver.
+func NewWithOptions(conn *sql.DB, tableName string, opts ...Option) (*Cache, error) {
 	if strings.TrimSpace(tableName) == "" {
 		return nil, errors.New("tableName must not be empty")
 	}
 
-	_, err := conn.Exec(fmt.Sprintf(`create table if not exists %s (
-		key  varchar(400) not null primary key, 
-		data bytea not null
-	);`, tableName))
-	if err != nil {
+	c := &Cache{
+		conn:       conn,
+		certs:      newLRUCache(defaultMaxCacheEntries),
+		dialect:    detectDialect(conn),
+		encryption: noopEncryptionStrategy{},
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	keyCol := c.dialect.QuoteIdentifier("key")
+
+	tableBody := fmt.Sprintf(`
+		%s         varchar(400) not null primary key,
+		data       %s not null,
+		nonce      %s,
+		key_id     varchar(100),
+		created_at %s not null default %s,
+		expires_at %s
+	`, keyCol, c.dialect.BlobType(), c.dialect.BlobType(), c.dialect.TimestampType(), c.dialect.CurrentTimestampExpr(), c.dialect.TimestampType())
+
+	if _, err := conn.Exec(c.dialect.CreateTableQuery(tableName, tableBody)); err != nil {
 		return nil, err
 	}
 
-	return &Cache{
-		conn:        conn,
-		certs:       make(map[string][]byte),
-		getQuery:    fmt.Sprintf(`SELECT data FROM %s`, tableName),
-		insertQuery: fmt.Sprintf(`INSERT INTO %s (key, data) VALUES($1, $2)`, tableName),
-		updateQuery: fmt.Sprintf(`UPDATE %s SET data = $2 WHERE key = $1`, tableName),
-		deleteQuery: fmt.Sprintf(`DELETE FROM %s WHERE key = $1`, tableName),
-	}, nil
+	// migrateColumn is a no-op against a table already created by this
+	// version, but upgrades a table created before nonce/key_id existed
+	// (create table if not exists above never alters an existing table).
+	if err := migrateColumn(conn, c.dialect, tableName, "nonce", c.dialect.BlobType()); err != nil {
+		return nil, err
+	}
+	if err := migrateColumn(conn, c.dialect, tableName, "key_id", "varchar(100)"); err != nil {
+		return nil, err
+	}
+	// created_at is NOT NULL, so back-fill it on migration with a default
+	// rather than leaving existing rows unable to satisfy the constraint.
+	createdAtDef := fmt.Sprintf("%s not null default %s", c.dialect.TimestampType(), c.dialect.CurrentTimestampExpr())
+	if err := migrateColumn(conn, c.dialect, tableName, "created_at", createdAtDef); err != nil {
+		return nil, err
+	}
+	if err := migrateColumn(conn, c.dialect, tableName, "expires_at", c.dialect.TimestampType()); err != nil {
+		return nil, err
+	}
+
+	columns := []string{"key", "data", "nonce", "key_id", "created_at", "expires_at"}
+	updatable := []string{"data", "nonce", "key_id", "expires_at"}
+
+	c.getQuery = fmt.Sprintf(`SELECT data, nonce, key_id FROM %s WHERE %s = %s`, tableName, keyCol, c.dialect.Placeholder(1))
+	c.deleteQuery = fmt.Sprintf(`DELETE FROM %s WHERE %s = %s`, tableName, keyCol, c.dialect.Placeholder(1))
+	c.upsertQuery = c.dialect.UpsertQuery(tableName, columns, updatable)
+	c.listQuery = fmt.Sprintf(`SELECT %s, created_at, expires_at FROM %s`, keyCol, tableName)
+	c.reapQuery = fmt.Sprintf(`DELETE FROM %s WHERE expires_at IS NOT NULL AND expires_at < %s`, tableName, c.dialect.Placeholder(1))
+
+	return c, nil
 }
 
 // Get returns a certificate data for the specified key.
 // If there's no such key, Get returns ErrCacheMiss.
 func (c *Cache) Get(ctx context.Context, key string) ([]byte, error) {
-	c.certsMu.RLock()
-	defer c.certsMu.RUnlock()
-
-	data, ok := c.certs[key]
+	c.certsMu.Lock()
+	data, ok := c.certs.Get(key)
+	c.certsMu.Unlock()
 	if ok {
 		return data, nil
 	}
 
-	row := c.conn.QueryRowContext(ctx, c.getQuery)
-	err := row.Scan(&data)
-	if err == sql.ErrNoRows {
-		return nil, autocert.ErrCacheMiss
+	var nonce []byte
+	var keyID sql.NullString
+	row := c.conn.QueryRowContext(ctx, c.getQuery, key)
+	if err := row.Scan(&data, &nonce, &keyID); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, autocert.ErrCacheMiss
+		}
+		return nil, err
 	}
-	return data, err
-}
 
-// Put stores the data in the cache under the specified key.
-func (c *Cache) Put(ctx context.Context, key string, data []byte) error {
+	plaintext := data
+	if nonce != nil {
+		var err error
+		if keyed, ok := c.encryption.(KeyedEncryptionStrategy); ok && keyID.Valid {
+			plaintext, err = keyed.DecryptWithKeyID(data, nonce, keyID.String)
+		} else {
+			plaintext, err = c.encryption.Decrypt(data, nonce)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	// else: legacy row written before encryption was enabled (or by a
+	// no-op strategy), so data is already plaintext.
+
 	c.certsMu.Lock()
-	defer c.certsMu.Unlock()
+	c.certs.Add(key, plaintext)
+	c.certsMu.Unlock()
 
-	result, err := c.conn.ExecContext(ctx, c.updateQuery, key, data)
+	return plaintext, nil
+}
+
+// Put stores the data in the cache under the specified key. The DB write
+// and the in-memory cache write are not atomic with each other: if two
+// Puts (or a Put and a Get repopulating the cache) race for the same key,
+// the LRU can end up holding an older value than the row committed last,
+// and keeps serving it until the entry is next overwritten, removed,
+// invalidated or evicted.
+func (c *Cache) Put(ctx context.Context, key string, data []byte) error {
+	ciphertext, nonce, err := c.encryption.Encrypt(data)
 	if err != nil {
 		return err
 	}
 
-	rowsAffected, err := result.RowsAffected()
+	var keyID sql.NullString
+	if nonce != nil {
+		if keyed, ok := c.encryption.(KeyedEncryptionStrategy); ok {
+			keyID = sql.NullString{String: keyed.CurrentKeyID(), Valid: true}
+		}
+	}
+
+	now := time.Now()
+	_, err = c.conn.ExecContext(ctx, c.upsertQuery, key, ciphertext, nonce, keyID, now, c.expiresAt(data, now))
 	if err != nil {
 		return err
 	}
 
-	if rowsAffected == 0 {
-		_, err := c.conn.ExecContext(ctx, c.insertQuery, key, data)
-		if err != nil {
-			return err
-		}
-	}
+	c.certsMu.Lock()
+	c.certs.Add(key, data)
+	c.certsMu.Unlock()
 
-	c.certs[key] = data
+	c.notify(ctx, key)
 	return nil
 }
 
 // Delete removes a certificate data from the cache under the specified key.
 // If there's no such key in the cache, Delete returns nil.
 func (c *Cache) Delete(ctx context.Context, key string) error {
-	c.certsMu.Lock()
-	defer c.certsMu.Unlock()
-
-	_, ok := c.certs[key]
-	if ok {
-		delete(c.certs, key)
-	}
-
 	_, err := c.conn.ExecContext(ctx, c.deleteQuery, key)
 	if err != nil {
 		return err
 	}
+
+	c.certsMu.Lock()
+	c.certs.Remove(key)
+	c.certsMu.Unlock()
+
+	c.notify(ctx, key)
 	return nil
 }
+
+// migrateColumn adds column to tableName via dialect's AddColumnQuery,
+// unless it's already present. PostgreSQL and SQL Server express this as a
+// single idempotent statement; MySQL and SQLite can't, so a "duplicate
+// column" error from re-adding a column that already exists is expected
+// there and ignored.
+func migrateColumn(conn *sql.DB, dialect Dialect, tableName, column, def string) error {
+	_, err := conn.Exec(dialect.AddColumnQuery(tableName, column, def))
+	if err != nil && isDuplicateColumnErr(err) {
+		return nil
+	}
+	return err
+}
+
+func isDuplicateColumnErr(err error) bool {
+	return strings.Contains(strings.ToLower(err.Error()), "duplicate column")
+}
@@ -0,0 +1,25 @@
+package sqlcertcache
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestIsDuplicateColumnErr(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"sqlite duplicate column", errors.New("duplicate column name: nonce"), true},
+		{"different error", errors.New("no such table: certs"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isDuplicateColumnErr(tt.err); got != tt.want {
+				t.Errorf("isDuplicateColumnErr(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}